@@ -0,0 +1,99 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+// Package graphql exposes a read-only GraphQL API over BlobReader for
+// external indexers and Merkle-proof builders, following the pattern of
+// ipld-eth-server's GraphQL API for getStorageAt/getLogs.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethstorage/go-ethstorage/ethstorage/downloader"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Backend is the read-only surface GraphQL resolvers run against.
+// *blobs.BlobReader satisfies it directly.
+type Backend interface {
+	GetBlob(kvIdx uint64, kvHash common.Hash) ([]byte, error)
+	ReadSample(shardIdx, sampleIdx uint64) (common.Hash, error)
+	ShardEncoding(shardIdx uint64) (encodeType uint64, miner common.Address)
+	CachedBlockBlobs(blockHash common.Hash) []downloader.Blob
+}
+
+// Auth gates resolver access so operators can allow cheap sample reads while
+// separately restricting the more expensive full-blob reads.
+type Auth interface {
+	AllowBlobRead(ctx context.Context) bool
+	AllowSampleRead(ctx context.Context) bool
+}
+
+type allowAll struct{}
+
+func (allowAll) AllowBlobRead(context.Context) bool   { return true }
+func (allowAll) AllowSampleRead(context.Context) bool { return true }
+
+// AllowAll is the default Auth used when an operator hasn't configured one.
+var AllowAll Auth = allowAll{}
+
+// Config configures the GraphQL HTTP server. The caller fills in Addr; this
+// package defines no command-line flag for it. An empty Addr does not disable
+// the server: Start then listens on a port chosen by the system.
+type Config struct {
+	Addr string
+	Auth Auth // optional, defaults to AllowAll
+}
+
+// Server serves the read-only BlobReader GraphQL API over HTTP.
+type Server struct {
+	http *http.Server
+	lg   log.Logger
+}
+
+// New builds the GraphQL server for backend. It does not start listening;
+// call Start.
+func New(backend Backend, cfg Config, lg log.Logger) (*Server, error) {
+	auth := cfg.Auth
+	if auth == nil {
+		auth = AllowAll
+	}
+	parsedSchema, err := graphql.ParseSchema(schema, &Resolver{backend: backend, auth: auth})
+	if err != nil {
+		return nil, fmt.Errorf("parse graphql schema: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", &relay.Handler{Schema: parsedSchema})
+	return &Server{
+		http: &http.Server{Addr: cfg.Addr, Handler: mux},
+		lg:   lg,
+	}, nil
+}
+
+// Start begins serving requests in a background goroutine.
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.http.Addr, err)
+	}
+	go func() {
+		if err := s.http.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.lg.Error("GraphQL server stopped unexpectedly", "err", err)
+		}
+	}()
+	s.lg.Info("GraphQL server started", "addr", l.Addr())
+	return nil
+}
+
+// Stop gracefully shuts the server down.
+func (s *Server) Stop(ctx context.Context) error {
+	s.lg.Info("Stopping GraphQL server")
+	return s.http.Shutdown(ctx)
+}
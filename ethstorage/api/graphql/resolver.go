@@ -0,0 +1,128 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethstorage/go-ethstorage/ethstorage/downloader"
+)
+
+var (
+	errBlobReadForbidden   = errors.New("blob reads are not permitted for this client")
+	errSampleReadForbidden = errors.New("sample reads are not permitted for this client")
+)
+
+// Resolver implements the Query type of schema against a Backend, gating
+// each field through Auth.
+type Resolver struct {
+	backend Backend
+	auth    Auth
+}
+
+type blobArgs struct {
+	KvIndex Long
+	KvHash  Bytes32
+}
+
+func (r *Resolver) Blob(ctx context.Context, args blobArgs) (*Bytes, error) {
+	if !r.auth.AllowBlobRead(ctx) {
+		return nil, errBlobReadForbidden
+	}
+	data, err := r.backend.GetBlob(uint64(args.KvIndex), common.Hash(args.KvHash))
+	if err != nil {
+		return nil, err
+	}
+	b := Bytes(data)
+	return &b, nil
+}
+
+// SampleRequest is both the arguments of the single-sample query and the
+// element type of the samples query's batch input.
+type SampleRequest struct {
+	ShardIndex  Long
+	SampleIndex Long
+}
+
+func (r *Resolver) Sample(ctx context.Context, args SampleRequest) (*Bytes32, error) {
+	if !r.auth.AllowSampleRead(ctx) {
+		return nil, errSampleReadForbidden
+	}
+	hash, err := r.backend.ReadSample(uint64(args.ShardIndex), uint64(args.SampleIndex))
+	if err != nil {
+		return nil, err
+	}
+	b := Bytes32(hash)
+	return &b, nil
+}
+
+type samplesArgs struct {
+	Requests []SampleRequest
+}
+
+func (r *Resolver) Samples(ctx context.Context, args samplesArgs) ([]*Bytes32, error) {
+	if !r.auth.AllowSampleRead(ctx) {
+		return nil, errSampleReadForbidden
+	}
+	out := make([]*Bytes32, len(args.Requests))
+	for i, req := range args.Requests {
+		hash, err := r.backend.ReadSample(uint64(req.ShardIndex), uint64(req.SampleIndex))
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i, err)
+		}
+		b := Bytes32(hash)
+		out[i] = &b
+	}
+	return out, nil
+}
+
+type shardArgs struct {
+	ShardIndex Long
+}
+
+// ShardEncodingResolver resolves the ShardEncoding GraphQL type.
+type ShardEncodingResolver struct {
+	encodeType uint64
+	miner      common.Address
+}
+
+func (s *ShardEncodingResolver) EncodeType() Long { return Long(s.encodeType) }
+func (s *ShardEncodingResolver) Miner() Address   { return Address(s.miner) }
+
+func (r *Resolver) ShardEncoding(ctx context.Context, args shardArgs) (*ShardEncodingResolver, error) {
+	if !r.auth.AllowSampleRead(ctx) {
+		return nil, errSampleReadForbidden
+	}
+	encodeType, miner := r.backend.ShardEncoding(uint64(args.ShardIndex))
+	return &ShardEncodingResolver{encodeType: encodeType, miner: miner}, nil
+}
+
+type cachedBlockBlobsArgs struct {
+	BlockHash Bytes32
+}
+
+// BlobResolver resolves the Blob GraphQL type.
+type BlobResolver struct {
+	blob downloader.Blob
+}
+
+func (b *BlobResolver) KvIndex() Long { return Long(b.blob.KvIdx()) }
+func (b *BlobResolver) Hash() Bytes32 { return Bytes32(b.blob.Hash()) }
+func (b *BlobResolver) Size() Long    { return Long(b.blob.Size()) }
+func (b *BlobResolver) Data() Bytes   { return Bytes(b.blob.Data()) }
+
+func (r *Resolver) CachedBlockBlobs(ctx context.Context, args cachedBlockBlobsArgs) ([]*BlobResolver, error) {
+	if !r.auth.AllowBlobRead(ctx) {
+		return nil, errBlobReadForbidden
+	}
+	blobs := r.backend.CachedBlockBlobs(common.Hash(args.BlockHash))
+	out := make([]*BlobResolver, len(blobs))
+	for i, b := range blobs {
+		out[i] = &BlobResolver{blob: b}
+	}
+	return out, nil
+}
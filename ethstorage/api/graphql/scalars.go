@@ -0,0 +1,106 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Long is a custom GraphQL scalar wrapping an int64, following the same
+// convention as go-ethereum's own eth/graphql package: it accepts either a
+// JSON number or a "0x"-prefixed hex string on input, and serializes back to
+// a hex string so values beyond JSON's safe integer range round-trip.
+type Long int64
+
+func (l Long) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		n, err := hexutil.DecodeUint64(input)
+		if err != nil {
+			return err
+		}
+		*l = Long(n)
+		return nil
+	case int32:
+		*l = Long(input)
+		return nil
+	case int64:
+		*l = Long(input)
+		return nil
+	case float64:
+		*l = Long(input)
+		return nil
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+}
+
+func (l Long) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", hexutil.Uint64(l).String())), nil
+}
+
+// Bytes is a custom GraphQL scalar for arbitrary-length byte slices,
+// represented on the wire as a "0x"-prefixed hex string.
+type Bytes []byte
+
+func (b Bytes) ImplementsGraphQLType(name string) bool { return name == "Bytes" }
+
+func (b *Bytes) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes", input)
+	}
+	decoded, err := hexutil.Decode(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", hexutil.Encode(b))), nil
+}
+
+// Bytes32 is a custom GraphQL scalar for a fixed 32-byte value such as a
+// kvHash or sample, represented on the wire as a "0x"-prefixed hex string.
+type Bytes32 common.Hash
+
+func (b Bytes32) ImplementsGraphQLType(name string) bool { return name == "Bytes32" }
+
+func (b *Bytes32) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes32", input)
+	}
+	*b = Bytes32(common.HexToHash(s))
+	return nil
+}
+
+func (b Bytes32) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", common.Hash(b).Hex())), nil
+}
+
+// Address is a custom GraphQL scalar for a 20-byte account address.
+type Address common.Address
+
+func (a Address) ImplementsGraphQLType(name string) bool { return name == "Address" }
+
+func (a *Address) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Address", input)
+	}
+	*a = Address(common.HexToAddress(s))
+	return nil
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", common.Address(a).Hex())), nil
+}
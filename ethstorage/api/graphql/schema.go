@@ -0,0 +1,56 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package graphql
+
+// schema is the read-only GraphQL schema served over BlobReader, modelled
+// on ipld-eth-server's GraphQL API for getStorageAt/getLogs: cheap, frequent
+// lookups (sample, samples, shardEncoding) sit alongside the more expensive
+// full-blob reads (blob, cachedBlockBlobs) so resolver-level auth can gate
+// them independently.
+const schema = `
+schema { query: Query }
+
+scalar Long
+scalar Bytes
+scalar Bytes32
+scalar Address
+
+type Query {
+  # blob returns the full plaintext kv value at kvIndex, verified against
+  # kvHash. Expensive relative to sample: gated by Auth.AllowBlobRead.
+  blob(kvIndex: Long!, kvHash: Bytes32!): Bytes
+
+  # sample returns one 32-byte encoded sample. Gated by Auth.AllowSampleRead.
+  sample(shardIndex: Long!, sampleIndex: Long!): Bytes32
+
+  # samples batches many sample lookups into a single round trip, for
+  # Merkle-proof builders that need thousands of samples per proof.
+  samples(requests: [SampleRequestInput!]!): [Bytes32]!
+
+  # shardEncoding reports the encode type and miner address a shard is
+  # currently configured with, needed to interpret its encoded samples.
+  shardEncoding(shardIndex: Long!): ShardEncoding
+
+  # cachedBlockBlobs lists the blobs the downloader currently holds for a
+  # block, before they are finalized into long-term storage.
+  cachedBlockBlobs(blockHash: Bytes32!): [Blob!]!
+}
+
+input SampleRequestInput {
+  shardIndex: Long!
+  sampleIndex: Long!
+}
+
+type ShardEncoding {
+  encodeType: Long!
+  miner: Address!
+}
+
+type Blob {
+  kvIndex: Long!
+  hash: Bytes32!
+  size: Long!
+  data: Bytes!
+}
+`
@@ -0,0 +1,169 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobs
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// encodedBlobEntry is one encodedBlobCache slot: the encoded blob bytes plus
+// a fingerprint of the (miner, encodeType, blobHash) triple that produced
+// them, so the downloader sync loop can tell whether a re-delivered blob
+// already has a valid encoding cached without re-running EncodeChunk.
+type encodedBlobEntry struct {
+	kvIdx       uint64
+	data        []byte
+	fingerprint common.Hash
+}
+
+// encodedBlobCache is a bounded, memory-budgeted LRU of encoded blobs keyed
+// by kvIdx. It replaces the old unbounded sync.Map, which grew for the
+// lifetime of the node since entries were never evicted. Eviction is driven
+// by a byte budget rather than an entry count, since every entry is a full
+// encoded blob (tens to hundreds of KB).
+type encodedBlobCache struct {
+	mu     sync.Mutex
+	budget uint64
+	used   uint64
+	ll     *list.List
+	items  map[uint64]*list.Element
+}
+
+func newEncodedBlobCache(budgetBytes uint64) *encodedBlobCache {
+	return &encodedBlobCache{
+		budget: budgetBytes,
+		ll:     list.New(),
+		items:  make(map[uint64]*list.Element),
+	}
+}
+
+// Get returns the cached encoded blob for kvIdx, promoting it to
+// most-recently-used on a hit.
+func (c *encodedBlobCache) Get(kvIdx uint64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[kvIdx]
+	if !ok {
+		blobCacheMisses.Inc()
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	blobCacheHits.Inc()
+	return el.Value.(*encodedBlobEntry).data, true
+}
+
+// Fingerprint returns the fingerprint cached for kvIdx, or the zero hash if
+// nothing is cached for it.
+func (c *encodedBlobCache) Fingerprint(kvIdx uint64) common.Hash {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[kvIdx]
+	if !ok {
+		return common.Hash{}
+	}
+	return el.Value.(*encodedBlobEntry).fingerprint
+}
+
+// Add inserts or replaces the encoded blob for kvIdx, then evicts the least
+// recently used entries until the cache fits within its byte budget.
+func (c *encodedBlobCache) Add(kvIdx uint64, data []byte, fingerprint common.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[kvIdx]; ok {
+		c.used -= uint64(len(el.Value.(*encodedBlobEntry).data))
+		c.ll.Remove(el)
+		delete(c.items, kvIdx)
+	}
+
+	c.items[kvIdx] = c.ll.PushFront(&encodedBlobEntry{kvIdx: kvIdx, data: data, fingerprint: fingerprint})
+	c.used += uint64(len(data))
+
+	for c.used > c.budget && c.ll.Len() > 0 {
+		back := c.ll.Back()
+		evicted := back.Value.(*encodedBlobEntry)
+		c.ll.Remove(back)
+		delete(c.items, evicted.kvIdx)
+		c.used -= uint64(len(evicted.data))
+	}
+}
+
+// sampleEntry is one sampleCache slot.
+type sampleEntry struct {
+	sampleIdx uint64
+	value     common.Hash
+}
+
+// sampleCache is a small, count-bounded LRU of recently requested samples,
+// letting ReadSample short-circuit for repeat mining attempts without
+// slicing an encoded blob out of encodedBlobCache.
+type sampleCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[uint64]*list.Element
+}
+
+func newSampleCache(capacity int) *sampleCache {
+	return &sampleCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[uint64]*list.Element),
+	}
+}
+
+// Get returns the cached sample value for sampleIdx, promoting it to
+// most-recently-used on a hit.
+func (c *sampleCache) Get(sampleIdx uint64) (common.Hash, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[sampleIdx]
+	if !ok {
+		sampleCacheMisses.Inc()
+		return common.Hash{}, false
+	}
+	c.ll.MoveToFront(el)
+	sampleCacheHits.Inc()
+	return el.Value.(*sampleEntry).value, true
+}
+
+// Add inserts or refreshes sampleIdx's cached value, evicting the least
+// recently used entry if the cache is over capacity.
+func (c *sampleCache) Add(sampleIdx uint64, value common.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[sampleIdx]; ok {
+		el.Value.(*sampleEntry).value = value
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[sampleIdx] = c.ll.PushFront(&sampleEntry{sampleIdx: sampleIdx, value: value})
+	for c.capacity > 0 && c.ll.Len() > c.capacity {
+		back := c.ll.Back()
+		c.ll.Remove(back)
+		delete(c.items, back.Value.(*sampleEntry).sampleIdx)
+	}
+}
+
+// RemoveRange evicts every cached sample in [lo, hi) - the sampleIdx range
+// owned by one kvIdx - so a stale sample encoded under a kv's previous
+// (miner, encodeType) is never served after that kv gets re-encoded.
+func (c *sampleCache) RemoveRange(lo, hi uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for sampleIdx, el := range c.items {
+		if sampleIdx >= lo && sampleIdx < hi {
+			c.ll.Remove(el)
+			delete(c.items, sampleIdx)
+		}
+	}
+}
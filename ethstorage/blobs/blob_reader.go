@@ -4,39 +4,73 @@
 package blobs
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	es "github.com/ethstorage/go-ethstorage/ethstorage"
 	"github.com/ethstorage/go-ethstorage/ethstorage/downloader"
+	"github.com/ethstorage/go-ethstorage/ethstorage/downloader/frozen"
 	"github.com/ethstorage/go-ethstorage/ethstorage/eth"
+	"github.com/ethstorage/go-ethstorage/ethstorage/p2p/blobgossip"
 )
 
 const (
 	BlobReaderSubKey = "blob-reader"
+
+	// defaultBlobCacheBudget bounds the memory encodedBlobCache may hold for
+	// encoded blobs. Encoded blobs are ~128 KB each, so this covers a few
+	// thousand recently synced kv entries.
+	defaultBlobCacheBudget = 512 * 1024 * 1024
+	// defaultSampleCacheSize bounds the number of hot samples kept in
+	// sampleCache.
+	defaultSampleCacheSize = 1 << 16
+	// gossipRequestDeadline bounds how long ReadSample waits on peers before
+	// falling back to the (disk-bound) storage manager.
+	gossipRequestDeadline = 500 * time.Millisecond
 )
 
 // BlobReader provides unified interface for the miner to read blobs and samples
 // from StorageManager and downloader cache.
 type BlobReader struct {
-	encodedBlobs sync.Map
-	dlr          *downloader.Downloader
-	sm           *es.StorageManager
-	l1           *eth.PollingClient
-	wg           sync.WaitGroup
-	exitCh       chan struct{}
-	lg           log.Logger
+	blobCache *encodedBlobCache // Bounded LRU of encoded blobs, keyed by kvIdx
+	samples   *sampleCache      // Bounded LRU of samples recently requested by mining attempts
+	dlr       *downloader.Downloader
+	sm        *es.StorageManager
+	l1        *eth.PollingClient
+	freezer   *frozen.Manager    // Optional long-term shelf consulted after the disk cache and storage manager miss
+	gossip    *blobgossip.Client // Optional peer sample gossip, tried before falling back to disk
+	wg        sync.WaitGroup
+	exitCh    chan struct{}
+	lg        log.Logger
 }
 
-func NewBlobReader(dlr *downloader.Downloader, sm *es.StorageManager, l1 *eth.PollingClient, lg log.Logger) *BlobReader {
+// NewBlobReader constructs a BlobReader. blobCacheBudget bounds the bytes
+// encodedBlobCache may hold and sampleCacheSize bounds the number of entries
+// in sampleCache; a zero/non-positive value for either falls back to its
+// package default.
+func NewBlobReader(dlr *downloader.Downloader, sm *es.StorageManager, l1 *eth.PollingClient, freezer *frozen.Manager, gossip *blobgossip.Client, blobCacheBudget uint64, sampleCacheSize int, lg log.Logger) *BlobReader {
+	if blobCacheBudget == 0 {
+		blobCacheBudget = defaultBlobCacheBudget
+	}
+	if sampleCacheSize <= 0 {
+		sampleCacheSize = defaultSampleCacheSize
+	}
 	n := &BlobReader{
-		dlr:    dlr,
-		sm:     sm,
-		l1:     l1,
-		lg:     lg,
-		exitCh: make(chan struct{}),
+		blobCache: newEncodedBlobCache(blobCacheBudget),
+		samples:   newSampleCache(sampleCacheSize),
+		dlr:       dlr,
+		sm:        sm,
+		l1:        l1,
+		freezer:   freezer,
+		gossip:    gossip,
+		lg:        lg,
+		exitCh:    make(chan struct{}),
 	}
 	n.sync()
 	return n
@@ -58,8 +92,7 @@ func (n *BlobReader) sync() {
 			select {
 			case blockHash := <-ch:
 				for _, blob := range n.dlr.Cache.Blobs(blockHash) {
-					encodedBlob := n.encodeBlob(blob)
-					n.encodedBlobs.Store(blob.KvIdx(), encodedBlob)
+					n.cacheEncodedBlob(blob)
 				}
 			case <-n.exitCh:
 				n.lg.Info("Blob reader is exiting from downloader sync loop...")
@@ -70,14 +103,72 @@ func (n *BlobReader) sync() {
 	n.wg.Add(1)
 }
 
-func (n *BlobReader) encodeBlob(blob downloader.Blob) []byte {
+// cacheEncodedBlob encodes blob for its shard's current (miner, encodeType)
+// and stores the result in blobCache, skipping the encode step entirely if
+// an identical encoding is already cached for that kvIdx - the sync loop
+// otherwise re-encodes every blob the downloader redelivers, even when
+// nothing about its encoding inputs has changed.
+func (n *BlobReader) cacheEncodedBlob(blob downloader.Blob) {
 	shardIdx := blob.KvIdx() >> n.sm.KvEntriesBits()
 	encodeType, _ := n.sm.GetShardEncodeType(shardIdx)
 	miner, _ := n.sm.GetShardMiner(shardIdx)
+
+	fingerprint := encodingFingerprint(miner, encodeType, blob.Hash())
+	if n.blobCache.Fingerprint(blob.KvIdx()) == fingerprint {
+		blobEncodeSkipped.Inc()
+		return
+	}
+
 	n.lg.Info("Encoding blob from downloader", "kvIdx", blob.KvIdx(), "shardIdx", shardIdx, "encodeType", encodeType, "miner", miner)
 	encodeKey := es.CalcEncodeKey(blob.Hash(), blob.KvIdx(), miner)
 	encodedBlob := es.EncodeChunk(blob.Size(), blob.Data(), encodeType, encodeKey)
-	return encodedBlob
+	n.blobCache.Add(blob.KvIdx(), encodedBlob, fingerprint)
+
+	// The samples sampleCache is holding for this kvIdx, if any, were sliced
+	// out of the encoding blobCache just replaced; they're stale the moment
+	// the encoding changes and must not be served again.
+	lo, hi := n.sampleRange(blob.KvIdx())
+	n.samples.RemoveRange(lo, hi)
+}
+
+// sampleRange returns the half-open [lo, hi) range of global sample indices
+// that belong to kvIdx's encoded blob.
+func (n *BlobReader) sampleRange(kvIdx uint64) (lo, hi uint64) {
+	sampleLenBits := n.sm.MaxKvSizeBits() - es.SampleSizeBits
+	lo = kvIdx << sampleLenBits
+	hi = (kvIdx + 1) << sampleLenBits
+	return lo, hi
+}
+
+// splitSampleIdx decomposes a global sample index into the kvIdx owning it
+// and that kv's local sample offset.
+func (n *BlobReader) splitSampleIdx(sampleIdx uint64) (kvIdx, sampleIdxInKv uint64) {
+	sampleLenBits := n.sm.MaxKvSizeBits() - es.SampleSizeBits
+	return sampleIdx >> sampleLenBits, sampleIdx % (1 << sampleLenBits)
+}
+
+// encodingFingerprint identifies the (miner, encodeType, blobHash) triple
+// that produced an encoded blob, so a re-delivered blob whose encoding
+// inputs haven't changed can be recognized without re-running EncodeChunk.
+func encodingFingerprint(miner common.Address, encodeType uint64, hash common.Hash) common.Hash {
+	var typeBytes [8]byte
+	binary.BigEndian.PutUint64(typeBytes[:], encodeType)
+	return crypto.Keccak256Hash(miner.Bytes(), typeBytes[:], hash.Bytes())
+}
+
+// ShardEncoding returns the encode type and miner address currently
+// configured for a shard, for external indexers that need to interpret
+// samples or blobs read from it.
+func (n *BlobReader) ShardEncoding(shardIdx uint64) (encodeType uint64, miner common.Address) {
+	encodeType, _ = n.sm.GetShardEncodeType(shardIdx)
+	miner, _ = n.sm.GetShardMiner(shardIdx)
+	return
+}
+
+// CachedBlockBlobs returns the blobs the downloader currently has cached for
+// blockHash, for read-only inspection by external indexers.
+func (n *BlobReader) CachedBlockBlobs(blockHash common.Hash) []downloader.Blob {
+	return n.dlr.Cache.Blobs(blockHash)
 }
 
 func (n *BlobReader) GetBlob(kvIdx uint64, kvHash common.Hash) ([]byte, error) {
@@ -90,30 +181,48 @@ func (n *BlobReader) GetBlob(kvIdx uint64, kvHash common.Hash) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if !exist {
-		return nil, fmt.Errorf("kv not found: index=%d", kvIdx)
+	if exist {
+		n.lg.Debug("Loaded blob from storage manager", "kvIdx", kvIdx)
+		return blob, nil
+	}
+	if n.freezer != nil {
+		if frozenBlob, ok, err := n.freezer.Get(kvIdx); err == nil && ok {
+			n.lg.Debug("Loaded blob from freezer", "kvIdx", kvIdx)
+			return frozenBlob, nil
+		}
 	}
-	n.lg.Debug("Loaded blob from storage manager", "kvIdx", kvIdx)
-	return blob, nil
+	return nil, fmt.Errorf("kv not found: index=%d", kvIdx)
 }
 
 func (n *BlobReader) ReadSample(shardIdx, sampleIdx uint64) (common.Hash, error) {
-	sampleLenBits := n.sm.MaxKvSizeBits() - es.SampleSizeBits
-	kvIdx := sampleIdx >> sampleLenBits
-
-	if value, ok := n.encodedBlobs.Load(kvIdx); ok {
-		encodedBlob := value.([]byte)
-		sampleIdxInKv := sampleIdx % (1 << sampleLenBits)
-		sampleSize := uint64(1 << es.SampleSizeBits)
-		sampleIdxByte := sampleIdxInKv << es.SampleSizeBits
-		sample := encodedBlob[sampleIdxByte : sampleIdxByte+sampleSize]
-		return common.BytesToHash(sample), nil
+	if sample, ok := n.samples.Get(sampleIdx); ok {
+		return sample, nil
+	}
+
+	kvIdx, sampleIdxInKv := n.splitSampleIdx(sampleIdx)
+
+	if encodedBlob, ok := n.blobCache.Get(kvIdx); ok {
+		sample := sampleAt(encodedBlob, sampleIdxInKv)
+		n.samples.Add(sampleIdx, sample)
+		return sample, nil
+	}
+
+	if n.gossip != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), gossipRequestDeadline)
+		sample, err := n.gossip.RequestSample(ctx, shardIdx, sampleIdx)
+		cancel()
+		if err == nil {
+			n.samples.Add(sampleIdx, sample)
+			return sample, nil
+		}
+		n.lg.Debug("Peer sample gossip missed, falling back to disk", "shardIdx", shardIdx, "sampleIdx", sampleIdx, "err", err)
 	}
 
 	encodedSample, err := n.sm.ReadSampleUnlocked(shardIdx, sampleIdx)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	n.samples.Add(sampleIdx, encodedSample)
 	return encodedSample, nil
 }
 
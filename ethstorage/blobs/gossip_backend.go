@@ -0,0 +1,101 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobs
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	es "github.com/ethstorage/go-ethstorage/ethstorage"
+	"github.com/ethstorage/go-ethstorage/ethstorage/p2p/blobgossip"
+)
+
+// BlobReader answers the responder half of the blobs/1 protocol
+// (blobgossip.Backend) and supplies its own requester half
+// (blobgossip.ShardEncoding, blobgossip.KvHashSource). Re-encoding is CPU
+// work only - the plaintext is already local via the downloader cache - so
+// it's cheaper than the disk-bound StorageManager read gossip exists to
+// avoid.
+var (
+	_ blobgossip.Backend       = (*BlobReader)(nil)
+	_ blobgossip.ShardEncoding = (*BlobReader)(nil)
+	_ blobgossip.KvHashSource  = (*BlobReader)(nil)
+)
+
+// ReadSampleFor re-encodes the sample at (shardIdx, sampleIdx) for miner and
+// encodeType, which may differ from this node's own shard configuration.
+func (n *BlobReader) ReadSampleFor(shardIdx, sampleIdx uint64, miner common.Address, encodeType uint64) (common.Hash, error) {
+	encodedBlob, sampleIdxInKv, err := n.encodeSampleBlobFor(sampleIdx, miner, encodeType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sampleAt(encodedBlob, sampleIdxInKv), nil
+}
+
+// ReadBlobForSample returns the plaintext blob backing sampleIdx, for a Raw
+// blobs/1 request.
+func (n *BlobReader) ReadBlobForSample(shardIdx, sampleIdx uint64) ([]byte, error) {
+	kvIdx, _ := n.splitSampleIdx(sampleIdx)
+	return n.rawBlobForKv(kvIdx)
+}
+
+// SampleProof returns the Merkle path proving the sample ReadSampleFor would
+// return for (miner, encodeType), in the same leaf-to-root order
+// blobgossip.verifySampleProof folds in.
+func (n *BlobReader) SampleProof(shardIdx, sampleIdx uint64, miner common.Address, encodeType uint64) ([][]byte, error) {
+	encodedBlob, sampleIdxInKv, err := n.encodeSampleBlobFor(sampleIdx, miner, encodeType)
+	if err != nil {
+		return nil, err
+	}
+	return sampleMerkleProof(encodedBlob, sampleIdxInKv)
+}
+
+// KvHash returns the root of the sample Merkle tree for this node's own
+// (miner, encodeType) on shardIdx, computed locally from the already-synced
+// plaintext so a blobs/1 response can be checked against it without
+// trusting the peer that served it.
+func (n *BlobReader) KvHash(shardIdx, sampleIdx uint64) (common.Hash, error) {
+	encodeType, miner := n.ShardEncoding(shardIdx)
+	encodedBlob, _, err := n.encodeSampleBlobFor(sampleIdx, miner, encodeType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sampleMerkleRoot(encodedBlob)
+}
+
+// encodeSampleBlobFor returns the encoded blob backing sampleIdx for
+// (miner, encodeType) and the sample's offset within it, reusing blobCache
+// when it already holds that exact encoding.
+func (n *BlobReader) encodeSampleBlobFor(sampleIdx uint64, miner common.Address, encodeType uint64) ([]byte, uint64, error) {
+	kvIdx, sampleIdxInKv := n.splitSampleIdx(sampleIdx)
+
+	raw, err := n.rawBlobForKv(kvIdx)
+	if err != nil {
+		return nil, 0, err
+	}
+	hash := crypto.Keccak256Hash(raw)
+
+	fingerprint := encodingFingerprint(miner, encodeType, hash)
+	if n.blobCache.Fingerprint(kvIdx) == fingerprint {
+		if cached, ok := n.blobCache.Get(kvIdx); ok {
+			return cached, sampleIdxInKv, nil
+		}
+	}
+
+	encodeKey := es.CalcEncodeKey(hash, kvIdx, miner)
+	encodedBlob := es.EncodeChunk(uint64(len(raw)), raw, encodeType, encodeKey)
+	return encodedBlob, sampleIdxInKv, nil
+}
+
+// rawBlobForKv returns the plaintext blob for kvIdx from the downloader's
+// disk cache, without attempting the disk-bound StorageManager read that
+// gossip exists to avoid.
+func (n *BlobReader) rawBlobForKv(kvIdx uint64) ([]byte, error) {
+	raw := n.dlr.Cache.GetKeyValueByIndexUnchecked(kvIdx)
+	if raw == nil {
+		return nil, fmt.Errorf("blobgossip: kv %d is not in the local downloader cache", kvIdx)
+	}
+	return raw, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Cache hit/miss counters let operators size encodedBlobCache's memory
+// budget and sampleCache's entry count from observed hit rates instead of
+// guessing.
+var (
+	blobCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "es_node",
+		Subsystem: "blob_reader",
+		Name:      "blob_cache_hits_total",
+		Help:      "Encoded-blob cache lookups served from memory.",
+	})
+	blobCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "es_node",
+		Subsystem: "blob_reader",
+		Name:      "blob_cache_misses_total",
+		Help:      "Encoded-blob cache lookups that missed and fell through to storage.",
+	})
+	sampleCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "es_node",
+		Subsystem: "blob_reader",
+		Name:      "sample_cache_hits_total",
+		Help:      "Sample reads served from the hot-sample cache.",
+	})
+	sampleCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "es_node",
+		Subsystem: "blob_reader",
+		Name:      "sample_cache_misses_total",
+		Help:      "Sample reads that missed the hot-sample cache.",
+	})
+	blobEncodeSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "es_node",
+		Subsystem: "blob_reader",
+		Name:      "blob_encode_skipped_total",
+		Help:      "Blobs delivered by the downloader that were not re-encoded because an identical (miner, encodeType, hash) encoding was already cached.",
+	})
+)
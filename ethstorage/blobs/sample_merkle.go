@@ -0,0 +1,79 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobs
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	es "github.com/ethstorage/go-ethstorage/ethstorage"
+)
+
+// sampleAt slices the sampleIdxInKv-th sample out of an encoded blob.
+func sampleAt(encodedBlob []byte, sampleIdxInKv uint64) common.Hash {
+	sampleSize := uint64(1) << es.SampleSizeBits
+	start := sampleIdxInKv << es.SampleSizeBits
+	return common.BytesToHash(encodedBlob[start : start+sampleSize])
+}
+
+// sampleMerkleTree builds the binary Merkle tree over an encoded blob's
+// samples, leaf-to-root, combining siblings via crypto.Keccak256Hash -
+// exactly the folding order blobgossip.verifySampleProof expects.
+func sampleMerkleTree(encodedBlob []byte) ([][]common.Hash, error) {
+	sampleSize := uint64(1) << es.SampleSizeBits
+	if len(encodedBlob) == 0 || uint64(len(encodedBlob))%sampleSize != 0 {
+		return nil, fmt.Errorf("encoded blob size %d is not a multiple of the sample size %d", len(encodedBlob), sampleSize)
+	}
+	n := uint64(len(encodedBlob)) / sampleSize
+
+	level := make([]common.Hash, n)
+	for i := range level {
+		level[i] = sampleAt(encodedBlob, uint64(i))
+	}
+
+	levels := [][]common.Hash{level}
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			return nil, fmt.Errorf("sample count %d is not a power of two", len(level))
+		}
+		next := make([]common.Hash, len(level)/2)
+		for i := range next {
+			next[i] = crypto.Keccak256Hash(level[2*i].Bytes(), level[2*i+1].Bytes())
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels, nil
+}
+
+// sampleMerkleRoot returns the root of encodedBlob's sample Merkle tree.
+func sampleMerkleRoot(encodedBlob []byte) (common.Hash, error) {
+	levels, err := sampleMerkleTree(encodedBlob)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return levels[len(levels)-1][0], nil
+}
+
+// sampleMerkleProof returns the sibling path from sampleIdxInKv's leaf up to
+// (but excluding) encodedBlob's sample Merkle root.
+func sampleMerkleProof(encodedBlob []byte, sampleIdxInKv uint64) ([][]byte, error) {
+	levels, err := sampleMerkleTree(encodedBlob)
+	if err != nil {
+		return nil, err
+	}
+	if sampleIdxInKv >= uint64(len(levels[0])) {
+		return nil, fmt.Errorf("sample index %d out of range for %d samples", sampleIdxInKv, len(levels[0]))
+	}
+
+	idx := sampleIdxInKv
+	proof := make([][]byte, 0, len(levels)-1)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := level[idx^1]
+		proof = append(proof, sibling.Bytes())
+		idx >>= 1
+	}
+	return proof, nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -15,6 +16,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethstorage/go-ethstorage/ethstorage"
+	"github.com/ethstorage/go-ethstorage/ethstorage/downloader/frozen"
 	"github.com/holiman/billy"
 )
 
@@ -25,19 +27,31 @@ const (
 )
 
 type BlobDiskCache struct {
-	store  billy.Database
-	lookup map[common.Hash]uint64 // Lookup table mapping hashes to blob billy entries id
-	lg     log.Logger
-	mu     sync.RWMutex
+	store   billy.Database
+	lookup  map[common.Hash]uint64 // Lookup table mapping hashes to blob billy entries id
+	kvIndex map[uint64]uint64      // Reverse lookup table mapping kvIdx to the billy entry id holding it most recently
+	freezer *frozen.Manager        // Optional long-term shelf for blobs evicted by Cleanup
+	lg      log.Logger
+	mu      sync.RWMutex
 }
 
 func NewBlobDiskCache(lg log.Logger) *BlobDiskCache {
 	return &BlobDiskCache{
-		lookup: make(map[common.Hash]uint64),
-		lg:     lg,
+		lookup:  make(map[common.Hash]uint64),
+		kvIndex: make(map[uint64]uint64),
+		lg:      lg,
 	}
 }
 
+// EnableFreezer points Cleanup at a frozen.Manager to migrate finalized blobs
+// into instead of discarding them. It must be called before the first
+// Cleanup that should be affected.
+func (c *BlobDiskCache) EnableFreezer(f *frozen.Manager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.freezer = f
+}
+
 func (c *BlobDiskCache) Init(datadir string) error {
 	cbdir := filepath.Join(datadir, blobCacheDir)
 	if err := os.MkdirAll(cbdir, 0700); err != nil {
@@ -67,6 +81,11 @@ func (c *BlobDiskCache) SetBlockBlobs(block *blockBlobs) error {
 
 	c.mu.Lock()
 	c.lookup[block.hash] = id
+	// A blob can be re-delivered under a different block due to a reorg;
+	// always point kvIndex at the most recently stored entry.
+	for _, blob := range block.blobs {
+		c.kvIndex[blob.kvIndex.Uint64()] = id
+	}
 	c.mu.Unlock()
 
 	c.lg.Debug("Set blockBlobs to cache", "id", id, "block", block.number)
@@ -111,17 +130,19 @@ func (c *BlobDiskCache) GetKeyValueByIndexUnchecked(idx uint64) []byte {
 
 func (c *BlobDiskCache) getBlobByIndex(idx uint64) *blob {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	id, ok := c.kvIndex[idx]
+	c.mu.RUnlock()
+	if !ok {
+		return nil
+	}
 
-	for _, id := range c.lookup {
-		block, err := c.getBlockBlobsById(id)
-		if err != nil || block == nil {
-			return nil
-		}
-		for _, blob := range block.blobs {
-			if blob.kvIndex.Uint64() == idx {
-				return blob
-			}
+	block, err := c.getBlockBlobsById(id)
+	if err != nil || block == nil {
+		return nil
+	}
+	for _, blob := range block.blobs {
+		if blob.kvIndex.Uint64() == idx {
+			return blob
 		}
 	}
 	return nil
@@ -131,6 +152,12 @@ func (c *BlobDiskCache) Cleanup(finalized uint64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	type finalizedEntry struct {
+		hash  common.Hash
+		id    uint64
+		block *blockBlobs
+	}
+	var finalizedEntries []finalizedEntry
 	for hash, id := range c.lookup {
 		block, err := c.getBlockBlobsById(id)
 		if err != nil {
@@ -138,12 +165,68 @@ func (c *BlobDiskCache) Cleanup(finalized uint64) {
 			continue
 		}
 		if block != nil && block.number <= finalized {
-			if err := c.store.Delete(id); err != nil {
-				c.lg.Error("Failed to delete block from id", "id", id, "err", err)
+			finalizedEntries = append(finalizedEntries, finalizedEntry{hash: hash, id: id, block: block})
+		}
+	}
+
+	// Map iteration order is randomized, but Freezer.Put requires kvIdx to be
+	// passed in increasing order per shard. Flatten every blob across all
+	// finalized entries and freeze them in kvIdx order instead of per-block.
+	failedIDs := make(map[uint64]bool)
+	if c.freezer != nil {
+		type pendingBlob struct {
+			id   uint64
+			blob *blob
+		}
+		var pending []pendingBlob
+		for _, fe := range finalizedEntries {
+			for _, blob := range fe.block.blobs {
+				pending = append(pending, pendingBlob{id: fe.id, blob: blob})
+			}
+		}
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].blob.kvIndex.Uint64() < pending[j].blob.kvIndex.Uint64()
+		})
+		for _, p := range pending {
+			if failedIDs[p.id] {
+				continue
+			}
+			kvIdx := p.blob.kvIndex.Uint64()
+			// A previous Cleanup call may have already frozen this kvIdx
+			// before a later blob in the same block failed; re-Put-ing it
+			// here would hit Freezer.Put's increasing-kvIdx guard and
+			// fail the block forever. Skip anything already durable.
+			if _, ok, err := c.freezer.Get(kvIdx); err == nil && ok {
+				continue
+			}
+			if err := c.freezer.Put(kvIdx, p.blob.data); err != nil {
+				c.lg.Error("Failed to freeze blob", "kvIdx", kvIdx, "err", err)
+				failedIDs[p.id] = true
+			}
+		}
+	}
+
+	for _, fe := range finalizedEntries {
+		hash, id, block := fe.hash, fe.id, fe.block
+		if failedIDs[id] {
+			// Freezing was supposed to happen but didn't succeed for every
+			// blob in this block; keep the billy entry around rather than
+			// losing data that was never actually durable anywhere else.
+			continue
+		}
+		if err := c.store.Delete(id); err != nil {
+			c.lg.Error("Failed to delete block from id", "id", id, "err", err)
+		}
+		delete(c.lookup, hash)
+		// Only drop the reverse entries that still point at this id; a reorg
+		// may already have repointed a kvIdx at a newer block's entry.
+		for _, blob := range block.blobs {
+			kvIdx := blob.kvIndex.Uint64()
+			if c.kvIndex[kvIdx] == id {
+				delete(c.kvIndex, kvIdx)
 			}
-			delete(c.lookup, hash)
-			c.lg.Info("Cleanup deleted", "finalized", finalized, "block", block.number, "id", id)
 		}
+		c.lg.Info("Cleanup deleted", "finalized", finalized, "block", block.number, "id", id)
 	}
 }
 
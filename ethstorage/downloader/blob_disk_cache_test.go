@@ -0,0 +1,109 @@
+// Copyright 2022-2023, EthStorage.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package downloader
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func newTestBlobDiskCache(t *testing.T) *BlobDiskCache {
+	t.Helper()
+	c := NewBlobDiskCache(log.New())
+	if err := c.Init(t.TempDir()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func testBlockBlobs(number uint64, hash common.Hash, kvIdx uint64, data []byte) *blockBlobs {
+	return &blockBlobs{
+		number: number,
+		hash:   hash,
+		blobs: []*blob{
+			{kvIndex: big.NewInt(int64(kvIdx)), hash: common.Hash{}, data: data},
+		},
+	}
+}
+
+// TestBlobDiskCache_ReorgReplacesKvIndex verifies that redelivering the same
+// kvIdx under a different block (as happens on a reorg) repoints kvIndex at
+// the newest entry, so getBlobByIndex never serves stale data.
+func TestBlobDiskCache_ReorgReplacesKvIndex(t *testing.T) {
+	c := newTestBlobDiskCache(t)
+
+	old := testBlockBlobs(1, common.HexToHash("0x01"), 5, []byte("old"))
+	if err := c.SetBlockBlobs(old); err != nil {
+		t.Fatalf("SetBlockBlobs(old): %v", err)
+	}
+	if got := c.GetKeyValueByIndexUnchecked(5); string(got) != "old" {
+		t.Fatalf("GetKeyValueByIndexUnchecked = %q, want %q", got, "old")
+	}
+
+	reorged := testBlockBlobs(1, common.HexToHash("0x02"), 5, []byte("new"))
+	if err := c.SetBlockBlobs(reorged); err != nil {
+		t.Fatalf("SetBlockBlobs(reorged): %v", err)
+	}
+	if got := c.GetKeyValueByIndexUnchecked(5); string(got) != "new" {
+		t.Fatalf("GetKeyValueByIndexUnchecked after reorg = %q, want %q", got, "new")
+	}
+}
+
+// TestBlobDiskCache_CleanupDoesNotOrphanReplacedKvIndex verifies that
+// cleaning up the stale (reorged-away) block entry does not drop the
+// kvIndex entry, since a reorg may already have repointed it at a newer
+// block's entry by the time Cleanup runs.
+func TestBlobDiskCache_CleanupDoesNotOrphanReplacedKvIndex(t *testing.T) {
+	c := newTestBlobDiskCache(t)
+
+	old := testBlockBlobs(1, common.HexToHash("0x01"), 5, []byte("old"))
+	if err := c.SetBlockBlobs(old); err != nil {
+		t.Fatalf("SetBlockBlobs(old): %v", err)
+	}
+	reorged := testBlockBlobs(2, common.HexToHash("0x02"), 5, []byte("new"))
+	if err := c.SetBlockBlobs(reorged); err != nil {
+		t.Fatalf("SetBlockBlobs(reorged): %v", err)
+	}
+
+	// The stale "old" entry is still in lookup under its own hash, but
+	// kvIndex[5] now points at "reorged". Finalizing only up to block 1
+	// must clean up "old" without deleting the kvIndex entry that "reorged"
+	// now owns.
+	c.Cleanup(1)
+
+	if _, ok := c.lookup[old.hash]; ok {
+		t.Fatalf("stale block entry %x was not cleaned up", old.hash)
+	}
+	if got := c.GetKeyValueByIndexUnchecked(5); string(got) != "new" {
+		t.Fatalf("kvIndex entry was orphaned by Cleanup: GetKeyValueByIndexUnchecked = %q, want %q", got, "new")
+	}
+}
+
+// TestBlobDiskCache_CleanupFinalized verifies that finalizing the block that
+// currently owns a kvIdx removes both the forward and reverse lookup
+// entries for it.
+func TestBlobDiskCache_CleanupFinalized(t *testing.T) {
+	c := newTestBlobDiskCache(t)
+
+	block := testBlockBlobs(1, common.HexToHash("0x01"), 5, []byte("finalized"))
+	if err := c.SetBlockBlobs(block); err != nil {
+		t.Fatalf("SetBlockBlobs: %v", err)
+	}
+
+	c.Cleanup(1)
+
+	if _, ok := c.lookup[block.hash]; ok {
+		t.Fatalf("finalized block entry %x was not cleaned up", block.hash)
+	}
+	if _, ok := c.kvIndex[5]; ok {
+		t.Fatalf("kvIndex entry for finalized kvIdx 5 was not cleaned up")
+	}
+	if got := c.GetKeyValueByIndexUnchecked(5); got != nil {
+		t.Fatalf("GetKeyValueByIndexUnchecked after cleanup = %q, want nil", got)
+	}
+}
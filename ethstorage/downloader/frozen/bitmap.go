@@ -0,0 +1,57 @@
+// Copyright 2022-2023, EthStorage.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package frozen
+
+// Bitmap is a simple growable bitset, one bit per relative slot, used by a
+// Freezer to mark which kvIdx within its tracked range have actually been
+// frozen (kvIdx is only guaranteed increasing, not contiguous).
+type Bitmap struct {
+	bits []byte
+}
+
+// NewBitmap returns an empty Bitmap.
+func NewBitmap() *Bitmap {
+	return &Bitmap{}
+}
+
+// bitmapFromBytes copies a previously persisted bitmap, so the result does not share raw's backing array.
+func bitmapFromBytes(raw []byte) *Bitmap {
+	return &Bitmap{bits: append([]byte(nil), raw...)}
+}
+
+// Set marks relative slot i as present.
+func (b *Bitmap) Set(i uint64) {
+	byteIdx := i / 8
+	for uint64(len(b.bits)) <= byteIdx {
+		b.bits = append(b.bits, 0)
+	}
+	b.bits[byteIdx] |= 1 << (i % 8)
+}
+
+// Has reports whether relative slot i was marked present.
+func (b *Bitmap) Has(i uint64) bool {
+	byteIdx := i / 8
+	if byteIdx >= uint64(len(b.bits)) {
+		return false
+	}
+	return b.bits[byteIdx]&(1<<(i%8)) != 0
+}
+
+// Truncate drops every bit at or beyond relative slot n.
+func (b *Bitmap) Truncate(n uint64) {
+	byteIdx := n / 8
+	if byteIdx >= uint64(len(b.bits)) {
+		return
+	}
+	if rem := n % 8; rem != 0 {
+		b.bits[byteIdx] &= (1 << rem) - 1
+		byteIdx++
+	}
+	b.bits = b.bits[:byteIdx]
+}
+
+// Bytes returns the bitmap's backing storage for persistence.
+func (b *Bitmap) Bytes() []byte {
+	return b.bits
+}
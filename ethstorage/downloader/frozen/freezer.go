@@ -0,0 +1,254 @@
+// Copyright 2022-2023, EthStorage.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+// Package frozen implements an append-only, mmap-friendly shelf for blobs
+// whose block has been finalized, modelled after go-ethereum's
+// core/rawdb/freezer ancient store: a single append-only data file plus a
+// compact index file; stored entries are never rewritten, only cut off by
+// Reset or crash recovery. It exists so BlobDiskCache no
+// longer has to choose between deleting finalized blobs outright or keeping
+// them in the (reorg-oriented) billy store forever.
+package frozen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	indexEntrySize = 6 // 2-byte file number + 4-byte offset, as in geth's freezer
+	headerSize     = 8 // base kvIdx, big-endian, prefixed to the index file
+
+	dataFileName   = "blobs.0.rdat"
+	indexFileName  = "blobs.ridx"
+	bitmapFileName = "blobs.bmap"
+)
+
+// indexEntry records where one entry's bytes end: they span
+// [entries[i-1].offset, entries[i].offset) of file entries[i].filenum.
+// filenum is carried even though today's Freezer never rotates past file 0,
+// so a future size-capped rotation doesn't have to touch the on-disk format.
+type indexEntry struct {
+	filenum uint16
+	offset  uint32
+}
+
+func (e indexEntry) marshal(b []byte) {
+	binary.BigEndian.PutUint16(b[:2], e.filenum)
+	binary.BigEndian.PutUint32(b[2:6], e.offset)
+}
+
+func (e *indexEntry) unmarshal(b []byte) {
+	e.filenum = binary.BigEndian.Uint16(b[:2])
+	e.offset = binary.BigEndian.Uint32(b[2:6])
+}
+
+// Freezer is a single shard's append-only blob shelf, keyed by kvIdx rather
+// than a contiguous item counter. Because a shard's kvIdx values need not be
+// frozen back-to-back, a Bitmap tracks which of the tracked range actually
+// hold data; the rest are gaps.
+type Freezer struct {
+	dir  string
+	base uint64 // kvIdx of entries[0]; meaningless while entries is empty
+
+	entries []indexEntry
+	bitmap  *Bitmap
+
+	data  *os.File
+	index *os.File
+
+	lg log.Logger
+	mu sync.RWMutex
+}
+
+// Open opens, or creates, the freezer shelf for a shard under datadir,
+// recovering from any partially-written tail a crash may have left behind.
+func Open(datadir string, shard uint64, lg log.Logger) (*Freezer, error) {
+	dir := filepath.Join(datadir, "frozen", fmt.Sprintf("shard%d", shard))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		lg.Error("Failed to create freezer directory", "dir", dir, "err", err)
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, dataFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		lg.Error("Failed to open freezer data file", "dir", dir, "err", err)
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, indexFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		data.Close()
+		lg.Error("Failed to open freezer index file", "dir", dir, "err", err)
+		return nil, err
+	}
+	f := &Freezer{dir: dir, data: data, index: index, bitmap: NewBitmap(), lg: lg}
+	if err := f.recover(); err != nil {
+		data.Close()
+		index.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// recover loads the index and bitmap from disk, truncating a partial
+// trailing index entry, or data written past the last valid entry, left by
+// a crash mid-write.
+func (f *Freezer) recover() error {
+	raw, err := os.ReadFile(filepath.Join(f.dir, indexFileName))
+	if err != nil {
+		return err
+	}
+	if len(raw) >= headerSize {
+		f.base = binary.BigEndian.Uint64(raw[:headerSize])
+		raw = raw[headerSize:]
+	} else {
+		raw = nil
+	}
+	if n := len(raw) % indexEntrySize; n != 0 {
+		f.lg.Warn("Truncating partial freezer index tail", "bytes", n)
+		raw = raw[:len(raw)-n]
+	}
+	f.entries = make([]indexEntry, len(raw)/indexEntrySize)
+	for i := range f.entries {
+		f.entries[i].unmarshal(raw[i*indexEntrySize : (i+1)*indexEntrySize])
+	}
+	if bmap, err := os.ReadFile(filepath.Join(f.dir, bitmapFileName)); err == nil {
+		f.bitmap = bitmapFromBytes(bmap)
+	}
+
+	want := int64(0)
+	if len(f.entries) > 0 {
+		want = int64(f.entries[len(f.entries)-1].offset)
+	}
+	if fi, err := f.data.Stat(); err == nil && fi.Size() > want {
+		f.lg.Warn("Truncating partial freezer data tail", "from", fi.Size(), "to", want)
+		if err := f.data.Truncate(want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put appends data for kvIdx to the shelf. kvIdx must be higher than every
+// index previously passed to Put since the last Reset; gaps between the
+// current tail and kvIdx are recorded in the bitmap as absent rather than
+// filled with data.
+func (f *Freezer) Put(kvIdx uint64, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.entries) == 0 {
+		f.base = kvIdx
+	}
+	rel := kvIdx - f.base
+	if kvIdx < f.base || rel < uint64(len(f.entries)) {
+		return fmt.Errorf("kvIdx %d is behind freezer tail (base=%d, len=%d)", kvIdx, f.base, len(f.entries))
+	}
+
+	tailOffset := uint32(0)
+	if len(f.entries) > 0 {
+		tailOffset = f.entries[len(f.entries)-1].offset
+	}
+	for uint64(len(f.entries)) < rel {
+		f.entries = append(f.entries, indexEntry{offset: tailOffset})
+	}
+
+	n, err := f.data.WriteAt(data, int64(tailOffset))
+	if err != nil {
+		return fmt.Errorf("write freezer data: %w", err)
+	}
+	f.entries = append(f.entries, indexEntry{offset: tailOffset + uint32(n)})
+	f.bitmap.Set(rel)
+
+	if err := f.flush(); err != nil {
+		return err
+	}
+	f.lg.Debug("Froze blob", "kvIdx", kvIdx, "size", n)
+	return nil
+}
+
+// Get returns the data frozen for kvIdx. ok is false if kvIdx was never
+// frozen, whether because it is out of the tracked range or a gap.
+func (f *Freezer) Get(kvIdx uint64) (data []byte, ok bool, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if len(f.entries) == 0 || kvIdx < f.base {
+		return nil, false, nil
+	}
+	rel := kvIdx - f.base
+	if rel >= uint64(len(f.entries)) || !f.bitmap.Has(rel) {
+		return nil, false, nil
+	}
+	start := uint32(0)
+	if rel > 0 {
+		start = f.entries[rel-1].offset
+	}
+	end := f.entries[rel].offset
+	data = make([]byte, end-start)
+	if _, err := f.data.ReadAt(data, int64(start)); err != nil {
+		return nil, false, fmt.Errorf("read freezer data: %w", err)
+	}
+	return data, true, nil
+}
+
+// Reset discards every entry at or above kvIdxLow, for unwinding a shard
+// after an L1 reorg rolls a previously finalized range back out.
+func (f *Freezer) Reset(kvIdxLow uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.entries) == 0 || kvIdxLow <= f.base {
+		f.entries = nil
+		f.bitmap = NewBitmap()
+		f.base = kvIdxLow
+		if err := f.data.Truncate(0); err != nil {
+			return err
+		}
+		return f.flush()
+	}
+	rel := kvIdxLow - f.base
+	if rel >= uint64(len(f.entries)) {
+		return nil
+	}
+	cut := uint32(0)
+	if rel > 0 {
+		cut = f.entries[rel-1].offset
+	}
+	f.entries = f.entries[:rel]
+	f.bitmap.Truncate(rel)
+	if err := f.data.Truncate(int64(cut)); err != nil {
+		return err
+	}
+	f.lg.Info("Freezer reset", "kvIdxLow", kvIdxLow)
+	return f.flush()
+}
+
+// flush persists the in-memory index and bitmap to disk.
+func (f *Freezer) flush() error {
+	buf := make([]byte, headerSize+len(f.entries)*indexEntrySize)
+	binary.BigEndian.PutUint64(buf[:headerSize], f.base)
+	for i, e := range f.entries {
+		e.marshal(buf[headerSize+i*indexEntrySize : headerSize+(i+1)*indexEntrySize])
+	}
+	if _, err := f.index.WriteAt(buf, 0); err != nil {
+		return fmt.Errorf("write freezer index: %w", err)
+	}
+	if err := f.index.Truncate(int64(len(buf))); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(f.dir, bitmapFileName), f.bitmap.Bytes(), 0644)
+}
+
+// Close releases the shelf's open file handles.
+func (f *Freezer) Close() error {
+	f.lg.Warn("Closing Freezer")
+	if err := f.data.Close(); err != nil {
+		return err
+	}
+	return f.index.Close()
+}
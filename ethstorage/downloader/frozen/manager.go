@@ -0,0 +1,169 @@
+// Copyright 2022-2023, EthStorage.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package frozen
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Manager multiplexes a set of per-shard Freezer shelves behind a single
+// kvIdx-addressed Put/Get/Reset, so callers that think in terms of kvIdx
+// (BlobDiskCache, BlobReader) don't need to know about shard layout.
+type Manager struct {
+	datadir           string
+	kvEntriesPerShard uint64
+	lg                log.Logger
+
+	mu     sync.RWMutex
+	shards map[uint64]*Freezer
+}
+
+// NewManager returns a Manager that lazily opens one Freezer per shard under
+// datadir, with each shard holding kvEntriesPerShard consecutive kvIdx.
+func NewManager(datadir string, kvEntriesPerShard uint64, lg log.Logger) *Manager {
+	return &Manager{
+		datadir:           datadir,
+		kvEntriesPerShard: kvEntriesPerShard,
+		lg:                lg,
+		shards:            make(map[uint64]*Freezer),
+	}
+}
+
+func (m *Manager) shardFor(kvIdx uint64) (uint64, error) {
+	if m.kvEntriesPerShard == 0 {
+		return 0, fmt.Errorf("frozen: kvEntriesPerShard not configured")
+	}
+	return kvIdx / m.kvEntriesPerShard, nil
+}
+
+func (m *Manager) freezer(shard uint64) (*Freezer, error) {
+	m.mu.RLock()
+	f, ok := m.shards[shard]
+	m.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.shards[shard]; ok {
+		return f, nil
+	}
+	f, err := Open(m.datadir, shard, m.lg)
+	if err != nil {
+		return nil, err
+	}
+	m.shards[shard] = f
+	return f, nil
+}
+
+// Put freezes data for kvIdx into the shard shelf that owns it.
+func (m *Manager) Put(kvIdx uint64, data []byte) error {
+	shard, err := m.shardFor(kvIdx)
+	if err != nil {
+		return err
+	}
+	f, err := m.freezer(shard)
+	if err != nil {
+		return err
+	}
+	return f.Put(kvIdx, data)
+}
+
+// Get reads data previously frozen for kvIdx, if any.
+func (m *Manager) Get(kvIdx uint64) ([]byte, bool, error) {
+	shard, err := m.shardFor(kvIdx)
+	if err != nil {
+		return nil, false, err
+	}
+	f, err := m.freezer(shard)
+	if err != nil {
+		return nil, false, err
+	}
+	return f.Get(kvIdx)
+}
+
+// Reset unwinds the shard owning kvIdxLow back to kvIdxLow, then wipes every
+// higher shard entirely: a reorg deep enough to unwind past a shard boundary
+// invalidates everything those shards ever froze, since it's all kvIdx >=
+// kvIdxLow. Shards not yet opened this run are discovered on disk, so a
+// shard frozen in a previous process lifetime and never touched since isn't
+// missed.
+func (m *Manager) Reset(kvIdxLow uint64) error {
+	shard, err := m.shardFor(kvIdxLow)
+	if err != nil {
+		return err
+	}
+	f, err := m.freezer(shard)
+	if err != nil {
+		return err
+	}
+	if err := f.Reset(kvIdxLow); err != nil {
+		return err
+	}
+
+	higher, err := m.shardsAbove(shard)
+	if err != nil {
+		return err
+	}
+	for _, s := range higher {
+		f, err := m.freezer(s)
+		if err != nil {
+			return err
+		}
+		if err := f.Reset(kvIdxLow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardsAbove returns every shard index greater than shard that has ever
+// been frozen to, whether or not this Manager has opened it this run.
+func (m *Manager) shardsAbove(shard uint64) ([]uint64, error) {
+	matches, err := filepath.Glob(filepath.Join(m.datadir, "frozen", "shard*"))
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	seen := make(map[uint64]bool, len(m.shards))
+	for s := range m.shards {
+		seen[s] = true
+	}
+	m.mu.RUnlock()
+	for _, match := range matches {
+		var s uint64
+		if _, err := fmt.Sscanf(filepath.Base(match), "shard%d", &s); err != nil {
+			continue
+		}
+		seen[s] = true
+	}
+
+	var higher []uint64
+	for s := range seen {
+		if s > shard {
+			higher = append(higher, s)
+		}
+	}
+	return higher, nil
+}
+
+// Close releases every shard shelf opened so far.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var firstErr error
+	for _, f := range m.shards {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
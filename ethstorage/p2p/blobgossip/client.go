@@ -0,0 +1,162 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobgossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	defaultDeadline = 500 * time.Millisecond
+	defaultPoolSize = 8
+)
+
+// PeerSource supplies the peers currently known to hold a shard.
+type PeerSource interface {
+	ShardPeers(shardIdx uint64) []peer.ID
+}
+
+// ShardEncoding supplies this node's own (miner, encodeType) for a shard, so
+// a request can tell the peer how to encode the sample it borrows rather
+// than accepting whatever encoding the peer happens to be configured with.
+type ShardEncoding interface {
+	ShardEncoding(shardIdx uint64) (encodeType uint64, miner common.Address)
+}
+
+// KvHashSource supplies the root a borrowed sample's Merkle proof must fold
+// up to for the kv that owns sampleIdx, so the proof is checked against a
+// value the requester computed itself instead of trusted on the peer's word.
+// The root is taken over the requester's own encoded samples; it is not the
+// kvHash committed on L1.
+type KvHashSource interface {
+	KvHash(shardIdx, sampleIdx uint64) (common.Hash, error)
+}
+
+// Client queries peers for samples over the blobs/1 protocol, bounded by a
+// worker pool and a short per-attempt deadline so a cold cache never stalls
+// mining longer than a local disk read would have.
+type Client struct {
+	host     host.Host
+	peers    PeerSource
+	encoding ShardEncoding
+	kvHashes KvHashSource
+	deadline time.Duration
+	pool     chan struct{} // bounds concurrent outstanding peer queries
+	lg       log.Logger
+}
+
+// NewClient returns a Client with the given bounded concurrency and
+// per-attempt deadline; non-positive values fall back to sane defaults.
+func NewClient(h host.Host, peers PeerSource, encoding ShardEncoding, kvHashes KvHashSource, poolSize int, deadline time.Duration, lg log.Logger) *Client {
+	if poolSize <= 0 {
+		poolSize = defaultPoolSize
+	}
+	if deadline <= 0 {
+		deadline = defaultDeadline
+	}
+	return &Client{
+		host:     h,
+		peers:    peers,
+		encoding: encoding,
+		kvHashes: kvHashes,
+		deadline: deadline,
+		pool:     make(chan struct{}, poolSize),
+		lg:       lg,
+	}
+}
+
+// RequestSample asks known peers for (shardIdx, sampleIdx) and returns the
+// first valid response, cancelling the rest. It returns an error if every
+// peer fails or the deadline passes first.
+func (c *Client) RequestSample(ctx context.Context, shardIdx, sampleIdx uint64) (common.Hash, error) {
+	peers := c.peers.ShardPeers(shardIdx)
+	if len(peers) == 0 {
+		return common.Hash{}, errors.New("blobgossip: no peers for shard")
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, c.deadline)
+	defer cancel()
+
+	type result struct {
+		sample common.Hash
+		err    error
+	}
+	results := make(chan result, len(peers))
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		p := p
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case c.pool <- struct{}{}:
+			case <-reqCtx.Done():
+				results <- result{err: reqCtx.Err()}
+				return
+			}
+			defer func() { <-c.pool }()
+			sample, err := c.requestFromPeer(reqCtx, p, shardIdx, sampleIdx)
+			results <- result{sample: sample, err: err}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var lastErr error
+	for res := range results {
+		if res.err == nil {
+			cancel()
+			return res.sample, nil
+		}
+		lastErr = res.err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("blobgossip: all peer requests failed")
+	}
+	return common.Hash{}, lastErr
+}
+
+func (c *Client) requestFromPeer(ctx context.Context, p peer.ID, shardIdx, sampleIdx uint64) (common.Hash, error) {
+	stream, err := c.host.NewStream(ctx, p, ProtocolID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer stream.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+	encodeType, miner := c.encoding.ShardEncoding(shardIdx)
+	req := Request{ShardIdx: shardIdx, SampleIdx: sampleIdx, Miner: miner, EncodeType: encodeType}
+	if err := rlp.Encode(stream, &req); err != nil {
+		return common.Hash{}, err
+	}
+	var resp Response
+	if err := rlp.Decode(stream, &resp); err != nil {
+		return common.Hash{}, err
+	}
+
+	kvHash, err := c.kvHashes.KvHash(shardIdx, sampleIdx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("blobgossip: failed to look up on-chain kvHash: %w", err)
+	}
+	if !verifySampleProof(kvHash, sampleIdx, resp.Sample, resp.Proof) {
+		return common.Hash{}, fmt.Errorf("blobgossip: peer %s returned a sample that failed proof verification", p)
+	}
+
+	c.lg.Debug("Borrowed sample from peer", "peer", p, "shardIdx", shardIdx, "sampleIdx", sampleIdx)
+	return resp.Sample, nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobgossip
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// verifySampleProof checks that sample, combined with proof's sibling
+// hashes in order from leaf to root, folds up to kvHash - the sample Merkle
+// root the requester computed itself (see KvHashSource) for the kv owning
+// sampleIdx, not the kvHash committed on L1. This
+// is what lets RequestSample treat a borrowed sample as trustworthy without
+// trusting the peer that served it.
+func verifySampleProof(kvHash common.Hash, sampleIdx uint64, sample common.Hash, proof [][]byte) bool {
+	h := sample
+	idx := sampleIdx
+	for _, sibling := range proof {
+		if idx&1 == 0 {
+			h = crypto.Keccak256Hash(h.Bytes(), sibling)
+		} else {
+			h = crypto.Keccak256Hash(sibling, h.Bytes())
+		}
+		idx >>= 1
+	}
+	return h == kvHash
+}
@@ -0,0 +1,42 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+// Package blobgossip implements the "blobs/1" request/response subprotocol,
+// which lets a miner borrow an encoded sample (or the plaintext blob it was
+// derived from) from a peer instead of waiting on its own disk-bound read.
+// This is most useful right after restart, when the local caches are cold
+// and every sample would otherwise cost a StorageManager read.
+package blobgossip
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// ProtocolID identifies the es-node sample gossip subprotocol.
+const ProtocolID protocol.ID = "/es/blobs/1"
+
+// Request asks a peer for one sample, encoded for the requester's own
+// (Miner, EncodeType) - a peer's shard is rarely configured with the same
+// miner/encodeType as the requester's, so the responder must re-encode on
+// the fly rather than handing back its own locally-encoded copy. If Raw is
+// set, the peer returns the plaintext blob (RawBlob) instead, and Miner/
+// EncodeType are ignored - useful when the requester wants to encode
+// locally rather than have the peer redundantly re-encode the same blob for
+// every distinct miner that asks.
+type Request struct {
+	ShardIdx   uint64
+	SampleIdx  uint64
+	Miner      common.Address
+	EncodeType uint64
+	Raw        bool
+}
+
+// Response carries either the encoded sample and a Merkle proof against the
+// sample Merkle root of the requested encoding (not the on-chain kvHash), or
+// (for a Raw request) the plaintext blob.
+type Response struct {
+	Sample  common.Hash
+	Proof   [][]byte
+	RawBlob []byte
+}
@@ -0,0 +1,119 @@
+// Copyright 2022-2023, es.
+// For license information, see https://github.com/ethstorage/es-node/blob/main/LICENSE
+
+package blobgossip
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/time/rate"
+)
+
+// Backend is what the server needs to answer a blobs/1 request.
+type Backend interface {
+	// ReadSampleFor re-encodes the sample at (shardIdx, sampleIdx) for the
+	// requesting miner/encodeType, which need not match this node's own
+	// shard configuration.
+	ReadSampleFor(shardIdx, sampleIdx uint64, miner common.Address, encodeType uint64) (common.Hash, error)
+	ReadBlobForSample(shardIdx, sampleIdx uint64) ([]byte, error)
+	// SampleProof returns the Merkle path proving the sample ReadSampleFor
+	// would return for (miner, encodeType), leaf-to-root, in the same order
+	// verifySampleProof folds them in.
+	SampleProof(shardIdx, sampleIdx uint64, miner common.Address, encodeType uint64) ([][]byte, error)
+}
+
+// ShardAuthorizer reports whether a peer is allowed to request samples from
+// a shard, so a responder only serves data to peers actually assigned to it.
+type ShardAuthorizer interface {
+	IsAssigned(p peer.ID, shardIdx uint64) bool
+}
+
+// Server answers blobs/1 requests, gating each requester by shard
+// assignment and a per-peer rate limit.
+type Server struct {
+	backend Backend
+	authz   ShardAuthorizer
+	lg      log.Logger
+
+	mu       sync.Mutex
+	limiters map[peer.ID]*rate.Limiter
+	rate     rate.Limit
+	burst    int
+}
+
+// NewServer registers the blobs/1 handler on h.
+func NewServer(h host.Host, backend Backend, authz ShardAuthorizer, ratePerSecond float64, burst int, lg log.Logger) *Server {
+	s := &Server{
+		backend:  backend,
+		authz:    authz,
+		lg:       lg,
+		limiters: make(map[peer.ID]*rate.Limiter),
+		rate:     rate.Limit(ratePerSecond),
+		burst:    burst,
+	}
+	h.SetStreamHandler(ProtocolID, s.handle)
+	return s
+}
+
+func (s *Server) limiterFor(p peer.ID) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.limiters[p]
+	if !ok {
+		l = rate.NewLimiter(s.rate, s.burst)
+		s.limiters[p] = l
+	}
+	return l
+}
+
+func (s *Server) handle(stream network.Stream) {
+	defer stream.Close()
+	p := stream.Conn().RemotePeer()
+
+	if !s.limiterFor(p).Allow() {
+		s.lg.Warn("Rejected blobs/1 request: rate limited", "peer", p)
+		return
+	}
+
+	var req Request
+	if err := rlp.Decode(stream, &req); err != nil {
+		s.lg.Debug("Failed to decode blobs/1 request", "peer", p, "err", err)
+		return
+	}
+	if !s.authz.IsAssigned(p, req.ShardIdx) {
+		s.lg.Warn("Rejected blobs/1 request: peer not assigned to shard", "peer", p, "shardIdx", req.ShardIdx)
+		return
+	}
+
+	var resp Response
+	if req.Raw {
+		blob, err := s.backend.ReadBlobForSample(req.ShardIdx, req.SampleIdx)
+		if err != nil {
+			s.lg.Debug("Failed to serve raw blob", "peer", p, "err", err)
+			return
+		}
+		resp.RawBlob = blob
+	} else {
+		sample, err := s.backend.ReadSampleFor(req.ShardIdx, req.SampleIdx, req.Miner, req.EncodeType)
+		if err != nil {
+			s.lg.Debug("Failed to serve sample", "peer", p, "err", err)
+			return
+		}
+		proof, err := s.backend.SampleProof(req.ShardIdx, req.SampleIdx, req.Miner, req.EncodeType)
+		if err != nil {
+			s.lg.Debug("Failed to build sample proof", "peer", p, "err", err)
+			return
+		}
+		resp.Sample, resp.Proof = sample, proof
+	}
+	if err := rlp.Encode(stream, &resp); err != nil {
+		s.lg.Debug("Failed to write blobs/1 response", "peer", p, "err", err)
+	}
+}